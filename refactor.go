@@ -7,6 +7,7 @@ import (
 	"github.com/getgauge/gauge/config"
 	"github.com/getgauge/gauge/gauge_messages"
 	"github.com/golang/protobuf/proto"
+	"io/ioutil"
 	"strings"
 )
 
@@ -14,6 +15,25 @@ type rephraseRefactorer struct {
 	oldStep   *step
 	newStep   *step
 	isConcept bool
+
+	// aliasIndex selects which alias of the implementation step should be
+	// rephrased, when the step has aliases. -1 means "not chosen yet".
+	aliasIndex int
+	// aliasText is filled in by getStepNameFromRunner once aliasIndex has
+	// resolved to an actual alias, so only that alias's usages are renamed.
+	aliasText string
+}
+
+// AliasSelectionRequiredError is returned when the step being refactored has
+// aliases and the caller hasn't said which one to rephrase via AliasIndex.
+// Aliases holds the full list so a CLI or API caller can prompt the user and
+// retry with AliasIndex set to the chosen position.
+type AliasSelectionRequiredError struct {
+	Aliases []string
+}
+
+func (e *AliasSelectionRequiredError) Error() string {
+	return fmt.Sprintf("steps with aliases : '%s' found. Set AliasIndex to choose which one to refactor.", strings.Join(e.Aliases, "', '"))
 }
 
 type refactoringResult struct {
@@ -23,13 +43,92 @@ type refactoringResult struct {
 	runnerFilesChanged []string
 	errors             []string
 	warnings           []string
+	preview            *refactoringPreview
+	// snapshotID is set when recoverFromFailedWrite persists a snapshot
+	// under KeepSnapshotsOnFailure, naming the directory `gauge refactor
+	// --undo` should replay.
+	snapshotID string
+}
+
+// RefactorOptions controls how PerformRephraseRefactoring behaves. The zero
+// value (no dry run, no alias, restore-on-failure) is the original refactor
+// behaviour.
+type RefactorOptions struct {
+	// DryRun, when true, runs the full pipeline without writing any spec,
+	// concept or runner file to disk; see refactoringResult.preview.
+	DryRun bool
+	// AliasIndex selects which alias to rephrase when the step has aliases.
+	// nil means "not specified": if the step turns out to have aliases,
+	// PerformRephraseRefactoring returns an *AliasSelectionRequiredError
+	// instead of guessing. A RefactorOptions{} zero value must not silently
+	// pick an alias, so this is a pointer rather than an int defaulting to
+	// the ambiguous 0.
+	AliasIndex *int
+	// KeepSnapshotsOnFailure, when true, leaves the pre-refactor snapshot of
+	// every spec/concept file in .gauge/refactor-<id>/ instead of restoring
+	// them automatically if the runner fails, so they can be inspected or
+	// replayed later with `gauge refactor --undo <id>`.
+	KeepSnapshotsOnFailure bool
+	// ReportXMLPath, when non-empty, is where a JUnit-style XML report of
+	// the refactoring run is written alongside the usual textual output,
+	// for the `--report-xml <path>` flag.
+	ReportXMLPath string
+}
+
+// PerformRephraseRefactoring runs the rephrase refactoring pipeline
+// according to opts. See RefactorOptions for the supported knobs. When
+// running as a step inside a GitHub Actions workflow, the result is also
+// printed as workflow commands so the changed files and any errors or
+// warnings show up natively in the PR UI.
+func PerformRephraseRefactoring(oldStep, newStep string, opts RefactorOptions) *refactoringResult {
+	result := performRephraseRefactoring(oldStep, newStep, opts)
+	if opts.ReportXMLPath != "" {
+		if err := writeJUnitXMLReport(result, opts.ReportXMLPath); err != nil {
+			result.errors = append(result.errors, fmt.Sprintf("Failed to write XML report to %s: %s", opts.ReportXMLPath, err))
+		}
+	}
+	if isGitHubActions() {
+		printRefactoringResultAsGitHubActionsCommands(result)
+	}
+	return result
 }
 
-func performRephraseRefactoring(oldStep, newStep string) *refactoringResult {
+// PerformRephraseRefactoringDryRun runs the rephrase refactoring pipeline as
+// a dry run and, when printDiff is true, writes the resulting unified diffs
+// to stdout. The preview is also returned on the result for editor/CI
+// integrations that want to render it themselves. aliasIndex is nil when
+// unspecified, same as RefactorOptions.AliasIndex.
+func PerformRephraseRefactoringDryRun(oldStep, newStep string, printDiff bool, aliasIndex *int) *refactoringResult {
+	result := performRephraseRefactoring(oldStep, newStep, RefactorOptions{DryRun: true, AliasIndex: aliasIndex})
+	if printDiff && result.preview != nil {
+		result.preview.print()
+	}
+	return result
+}
+
+// PerformRefactoringUndo replays the snapshot saved under
+// .gauge/refactor-<id>/ back over the tree, for `gauge refactor --undo <id>`.
+// id is the snapshotID a prior PerformRephraseRefactoring call returned on
+// refactoringResult when it was run with RefactorOptions.KeepSnapshotsOnFailure.
+func PerformRefactoringUndo(id string) error {
+	return performRefactoringUndo(id)
+}
+
+// resolveAliasIndexOption converts the "not specified" state of an optional
+// alias index (nil) into the -1 sentinel rephraseRefactorer and
+// getStepNameFromRunner use internally.
+func resolveAliasIndexOption(aliasIndex *int) int {
+	if aliasIndex == nil {
+		return -1
+	}
+	return *aliasIndex
+}
+
+func performRephraseRefactoring(oldStep, newStep string, opts RefactorOptions) *refactoringResult {
 	if newStep == oldStep {
 		return rephraseFailure("Same old step name and new step name.")
 	}
-	agent, err := getRefactorAgent(oldStep, newStep)
+	agent, err := getRefactorAgent(oldStep, newStep, resolveAliasIndexOption(opts.AliasIndex))
 
 	if err != nil {
 		return rephraseFailure(err.Error())
@@ -53,7 +152,7 @@ func performRephraseRefactoring(oldStep, newStep string) *refactoringResult {
 		return result
 	}
 
-	refactorResult := agent.performRefactoringOn(specs, conceptDictionary)
+	refactorResult := agent.performRefactoringOn(specs, conceptDictionary, opts)
 	refactorResult.warnings = append(refactorResult.warnings, result.warnings...)
 	return refactorResult
 }
@@ -72,11 +171,13 @@ func addErrorsAndWarningsToRefactoringResult(refactorResult *refactoringResult,
 	}
 }
 
-func (agent *rephraseRefactorer) performRefactoringOn(specs []*specification, conceptDictionary *conceptDictionary) *refactoringResult {
-	specsRefactored, conceptFilesRefactored := agent.rephraseInSpecsAndConcepts(&specs, conceptDictionary)
-	specFiles, conceptFiles := writeToConceptAndSpecFiles(specs, conceptDictionary, specsRefactored, conceptFilesRefactored)
-	refactoringResult := &refactoringResult{specsChanged: specFiles, success: false, conceptsChanged: conceptFiles, errors: make([]string, 0)}
+func (agent *rephraseRefactorer) performRefactoringOn(specs []*specification, conceptDictionary *conceptDictionary, opts RefactorOptions) *refactoringResult {
+	refactoringResult := &refactoringResult{success: false, errors: make([]string, 0)}
 
+	// The runner is consulted before specs/concepts are touched: when the
+	// step has aliases, getStepNameFromRunner is what resolves aliasText,
+	// and rephraseInSpecsAndConcepts needs that to rename only the chosen
+	// alias's usages.
 	runner, connErr := agent.startRunner()
 	if connErr != nil {
 		refactoringResult.errors = append(refactoringResult.errors, connErr.Error())
@@ -88,9 +189,27 @@ func (agent *rephraseRefactorer) performRefactoringOn(specs []*specification, co
 		refactoringResult.errors = append(refactoringResult.errors, err.Error())
 		return refactoringResult
 	}
-	runnerFilesChanged, err := agent.requestRunnerForRefactoring(runner, stepName)
+
+	specsRefactored, conceptFilesRefactored := agent.rephraseInSpecsAndConcepts(&specs, conceptDictionary)
+
+	if opts.DryRun {
+		return agent.previewRefactoringOn(runner, stepName, specs, conceptDictionary, specsRefactored, conceptFilesRefactored)
+	}
+
+	snapshots, err := snapshotFilesToBeWritten(specs, conceptDictionary, specsRefactored, conceptFilesRefactored)
+	if err != nil {
+		refactoringResult.errors = append(refactoringResult.errors, err.Error())
+		return refactoringResult
+	}
+
+	specFiles, conceptFiles := writeToConceptAndSpecFiles(specs, conceptDictionary, specsRefactored, conceptFilesRefactored)
+	refactoringResult.specsChanged = specFiles
+	refactoringResult.conceptsChanged = conceptFiles
+
+	runnerFilesChanged, err := agent.requestRunnerForRefactoring(runner, stepName, false)
 	if err != nil {
 		refactoringResult.errors = append(refactoringResult.errors, fmt.Sprintf("Only spec files and concepts refactored: %s", err))
+		agent.recoverFromFailedWrite(snapshots, refactoringResult, opts)
 		return refactoringResult
 	}
 	refactoringResult.success = true
@@ -98,12 +217,104 @@ func (agent *rephraseRefactorer) performRefactoringOn(specs []*specification, co
 	return refactoringResult
 }
 
+// recoverFromFailedWrite undoes the effect of writeToConceptAndSpecFiles
+// after the runner round-trip fails. By default every snapshotted file is
+// restored to disk and specsChanged/conceptsChanged on result are cleared,
+// so the tree is left exactly as it was found. When opts.KeepSnapshotsOnFailure
+// is set, the snapshots are persisted to .gauge/refactor-<id>/ instead, and
+// the run id is recorded on the result so `gauge refactor --undo <id>` can
+// replay them later.
+func (agent *rephraseRefactorer) recoverFromFailedWrite(snapshots map[string][]byte, result *refactoringResult, opts RefactorOptions) {
+	if opts.KeepSnapshotsOnFailure {
+		runID, err := persistRefactorSnapshot(snapshots)
+		if err != nil {
+			result.errors = append(result.errors, fmt.Sprintf("Failed to persist refactor snapshot: %s", err))
+			return
+		}
+		result.snapshotID = runID
+		return
+	}
+	if err := restoreSnapshots(snapshots); err != nil {
+		result.errors = append(result.errors, fmt.Sprintf("Failed to restore files after failed refactor: %s", err))
+		return
+	}
+	result.specsChanged = nil
+	result.conceptsChanged = nil
+}
+
+// previewRefactoringOn computes what performRefactoringOn would change
+// without touching disk. Spec and concept files are diffed against their
+// current on-disk contents. The runner is still asked to refactor, but with
+// RefactorRequest.DryRun set; runners that honour the flag return the
+// proposed file contents instead of writing them. Runners that don't
+// recognise the flag will write to disk as usual, so the original bytes are
+// snapshotted beforehand and restored once the response comes back.
+func (agent *rephraseRefactorer) previewRefactoringOn(runner *testRunner, stepName string, specs []*specification, conceptDictionary *conceptDictionary, specsRefactored map[*specification]bool, conceptFilesRefactored map[string]bool) *refactoringResult {
+	preview := &refactoringPreview{}
+	refactoringResult := &refactoringResult{success: false, errors: make([]string, 0), preview: preview}
+
+	for _, spec := range specs {
+		if !specsRefactored[spec] {
+			continue
+		}
+		diff, err := newFileDiff(spec.fileName, formatSpecification(spec))
+		if err != nil {
+			refactoringResult.errors = append(refactoringResult.errors, err.Error())
+			continue
+		}
+		preview.specs = append(preview.specs, diff)
+		refactoringResult.specsChanged = append(refactoringResult.specsChanged, spec.fileName)
+	}
+
+	conceptMap := formatConcepts(conceptDictionary)
+	for fileName, concept := range conceptMap {
+		if !conceptFilesRefactored[fileName] {
+			continue
+		}
+		diff, err := newFileDiff(fileName, concept)
+		if err != nil {
+			refactoringResult.errors = append(refactoringResult.errors, err.Error())
+			continue
+		}
+		preview.concepts = append(preview.concepts, diff)
+		refactoringResult.conceptsChanged = append(refactoringResult.conceptsChanged, fileName)
+	}
+
+	runnerFiles, err := agent.previewRunnerRefactoring(runner, stepName)
+	if err != nil {
+		refactoringResult.errors = append(refactoringResult.errors, fmt.Sprintf("Only spec files and concepts previewed: %s", err))
+		return refactoringResult
+	}
+	preview.runnerFiles = runnerFiles
+	for _, diff := range runnerFiles {
+		refactoringResult.runnerFilesChanged = append(refactoringResult.runnerFilesChanged, diff.fileName)
+	}
+	refactoringResult.success = true
+	return refactoringResult
+}
+
+// aliasScopedMatchValue returns the step value usages should be matched
+// against during rename: stepValue itself when the step has no aliases (or
+// none was resolved), or aliasText when one was. renameSteps/step.rename
+// already match usages by comparing against a step's value, so overriding
+// just the value passed in - rather than teaching those methods a new
+// alias-aware signature - is enough to make them match only the chosen
+// alias's own usages and leave the step's other aliases untouched.
+func aliasScopedMatchValue(stepValue, aliasText string) string {
+	if aliasText == "" {
+		return stepValue
+	}
+	return aliasText
+}
+
 func (agent *rephraseRefactorer) rephraseInSpecsAndConcepts(specs *[]*specification, conceptDictionary *conceptDictionary) (map[*specification]bool, map[string]bool) {
 	specsRefactored := make(map[*specification]bool, 0)
 	conceptFilesRefactored := make(map[string]bool, 0)
 	orderMap := agent.createOrderOfArgs()
+	oldStep := *agent.oldStep
+	oldStep.value = aliasScopedMatchValue(agent.oldStep.value, agent.aliasText)
 	for _, spec := range *specs {
-		specsRefactored[spec] = spec.renameSteps(*agent.oldStep, *agent.newStep, orderMap)
+		specsRefactored[spec] = spec.renameSteps(oldStep, *agent.newStep, orderMap)
 	}
 	isConcept := false
 	for _, concept := range conceptDictionary.conceptsMap {
@@ -112,7 +323,7 @@ func (agent *rephraseRefactorer) rephraseInSpecsAndConcepts(specs *[]*specificat
 		for _, item := range concept.conceptStep.items {
 			isRefactored := conceptFilesRefactored[concept.fileName]
 			conceptFilesRefactored[concept.fileName] = item.kind() == stepKind &&
-				item.(*step).rename(*agent.oldStep, *agent.newStep, isRefactored, orderMap, &isConcept) ||
+				item.(*step).rename(oldStep, *agent.newStep, isRefactored, orderMap, &isConcept) ||
 				isRefactored
 		}
 	}
@@ -137,7 +348,7 @@ func SliceIndex(limit int, predicate func(i int) bool) int {
 	return -1
 }
 
-func getRefactorAgent(oldStepText, newStepText string) (*rephraseRefactorer, error) {
+func getRefactorAgent(oldStepText, newStepText string, aliasIndex int) (*rephraseRefactorer, error) {
 	parser := new(specParser)
 	stepTokens, err := parser.generateTokens("* " + oldStepText + "\n" + "*" + newStepText)
 	if err != nil {
@@ -152,11 +363,11 @@ func getRefactorAgent(oldStepText, newStepText string) (*rephraseRefactorer, err
 		}
 		steps = append(steps, step)
 	}
-	return &rephraseRefactorer{oldStep: steps[0], newStep: steps[1]}, nil
+	return &rephraseRefactorer{oldStep: steps[0], newStep: steps[1], aliasIndex: aliasIndex}, nil
 }
 
-func (agent *rephraseRefactorer) requestRunnerForRefactoring(testRunner *testRunner, stepName string) ([]string, error) {
-	refactorRequest, err := agent.createRefactorRequest(testRunner, stepName)
+func (agent *rephraseRefactorer) requestRunnerForRefactoring(testRunner *testRunner, stepName string, dryRun bool) ([]string, error) {
+	refactorRequest, err := agent.createRefactorRequest(testRunner, stepName, dryRun)
 	if err != nil {
 		return nil, err
 	}
@@ -169,6 +380,66 @@ func (agent *rephraseRefactorer) requestRunnerForRefactoring(testRunner *testRun
 	return refactorResponse.GetFilesChanged(), runnerError
 }
 
+// previewRunnerRefactoring asks the runner to refactor with DryRun set. A
+// runner that understands the flag returns proposed file contents in
+// RefactorResponse.FileDiffs and leaves disk untouched. A runner built
+// against an older gauge_messages version silently ignores the unknown
+// field and refactors in place as normal; since that can't be detected up
+// front, the affected files are snapshotted first and restored afterwards.
+func (agent *rephraseRefactorer) previewRunnerRefactoring(testRunner *testRunner, stepName string) ([]*fileDiff, error) {
+	refactorRequest, err := agent.createRefactorRequest(testRunner, stepName, true)
+	if err != nil {
+		return nil, err
+	}
+
+	// The runner tells us which of its own files it touched only in the
+	// response, by which point a runner that ignores DryRun has already
+	// written them. Snapshot the whole project tree up front so there's
+	// something to restore from regardless of which files turn out to be
+	// affected - but only pay for the restore (and treat it as needed at
+	// all) once the response below actually shows the runner ignored
+	// DryRun. A runner that honours the flag returns FileDiffs having
+	// touched nothing, so there's nothing to roll back and no reason to
+	// rewrite every file in the project on the happy path.
+	projectRoot, err := common.GetProjectRoot()
+	if err != nil {
+		return nil, err
+	}
+	snapshots, err := snapshotProjectTree(projectRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	refactorResponse := agent.sendRefactorRequest(testRunner, refactorRequest)
+	if !refactorResponse.GetSuccess() {
+		apiLog.Error("Refactoring error response from runner: %v", refactorResponse.GetError())
+		return nil, errors.New(refactorResponse.GetError())
+	}
+
+	if protoDiffs := refactorResponse.GetFileDiffs(); len(protoDiffs) > 0 {
+		diffs := make([]*fileDiff, 0, len(protoDiffs))
+		for _, d := range protoDiffs {
+			diffs = append(diffs, newFileDiffFromBytes(d.GetFileName(), d.GetOriginalContent(), d.GetProposedContent()))
+		}
+		return diffs, nil
+	}
+
+	// Runner ignored DryRun and wrote straight to disk. Diff the snapshots
+	// taken above against what's there now, then restore them - removing
+	// any file the runner created that has no snapshot entry - so the dry
+	// run leaves the tree untouched.
+	defer restoreProjectSnapshot(projectRoot, snapshots)
+	diffs := make([]*fileDiff, 0, len(snapshots))
+	for _, fileName := range refactorResponse.GetFilesChanged() {
+		proposed, readErr := ioutil.ReadFile(fileName)
+		if readErr != nil {
+			continue
+		}
+		diffs = append(diffs, newFileDiffFromBytes(fileName, snapshots[fileName], proposed))
+	}
+	return diffs, nil
+}
+
 func (agent *rephraseRefactorer) startRunner() (*testRunner, error) {
 	loadGaugeEnvironment()
 	startAPIService(0)
@@ -188,7 +459,7 @@ func (agent *rephraseRefactorer) sendRefactorRequest(testRunner *testRunner, ref
 }
 
 //Todo: Check for inline tables
-func (agent *rephraseRefactorer) createRefactorRequest(runner *testRunner, stepName string) (*gauge_messages.Message, error) {
+func (agent *rephraseRefactorer) createRefactorRequest(runner *testRunner, stepName string, dryRun bool) (*gauge_messages.Message, error) {
 	oldStepValue, err := agent.getStepValueFor(agent.oldStep, stepName)
 	if err != nil {
 		return nil, err
@@ -201,7 +472,7 @@ func (agent *rephraseRefactorer) createRefactorRequest(runner *testRunner, stepN
 	}
 	oldProtoStepValue := convertToProtoStepValue(oldStepValue)
 	newProtoStepValue := convertToProtoStepValue(newStepValue)
-	return &gauge_messages.Message{MessageType: gauge_messages.Message_RefactorRequest.Enum(), RefactorRequest: &gauge_messages.RefactorRequest{OldStepValue: oldProtoStepValue, NewStepValue: newProtoStepValue, ParamPositions: agent.createParameterPositions(orderMap)}}, nil
+	return &gauge_messages.Message{MessageType: gauge_messages.Message_RefactorRequest.Enum(), RefactorRequest: &gauge_messages.RefactorRequest{OldStepValue: oldProtoStepValue, NewStepValue: newProtoStepValue, ParamPositions: agent.createParameterPositions(orderMap), DryRun: proto.Bool(dryRun), AliasIndex: proto.Int(agent.aliasIndex)}}, nil
 }
 
 func (agent *rephraseRefactorer) generateNewStepName(args []string, orderMap map[int]int) string {
@@ -227,10 +498,31 @@ func (agent *rephraseRefactorer) getStepNameFromRunner(runner *testRunner) (stri
 	if !(responseMessage.GetStepNameResponse().GetIsStepPresent()) {
 		return "", errors.New(fmt.Sprintf("Step implementation not found: %s", agent.oldStep.lineText))
 	}
+	stepName, err := resolveStepNameForAlias(responseMessage.GetStepNameResponse().GetStepName(), responseMessage.GetStepNameResponse().GetHasAlias(), agent.aliasIndex)
+	if err != nil {
+		return "", err
+	}
 	if responseMessage.GetStepNameResponse().GetHasAlias() {
-		return "", errors.New(fmt.Sprintf("steps with aliases : '%s' cannot be refactored.", strings.Join(responseMessage.GetStepNameResponse().GetStepName(), "', '")))
+		agent.aliasText = stepName
+	}
+	return stepName, nil
+}
+
+// resolveStepNameForAlias picks which of an implementation's stepNames to
+// rephrase. When hasAlias is false, stepNames holds exactly the step's own
+// name. When hasAlias is true, stepNames holds every alias and aliasIndex
+// (the -1 sentinel, or out of range) must name one of them, or refactoring
+// can't tell which alias's usages to rename and an
+// AliasSelectionRequiredError is returned so the caller can prompt for one
+// and retry.
+func resolveStepNameForAlias(stepNames []string, hasAlias bool, aliasIndex int) (string, error) {
+	if !hasAlias {
+		return stepNames[0], nil
+	}
+	if aliasIndex < 0 || aliasIndex >= len(stepNames) {
+		return "", &AliasSelectionRequiredError{Aliases: stepNames}
 	}
-	return responseMessage.GetStepNameResponse().GetStepName()[0], nil
+	return stepNames[aliasIndex], nil
 }
 
 func (agent *rephraseRefactorer) createParameterPositions(orderMap map[int]int) []*gauge_messages.ParameterPosition {
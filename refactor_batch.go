@@ -0,0 +1,198 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/getgauge/common"
+	"github.com/getgauge/gauge/config"
+	"github.com/getgauge/gauge/gauge_messages"
+	"gopkg.in/yaml.v2"
+)
+
+// stepRephrasePair is a single {old, new} entry in a batch refactoring
+// manifest.
+type stepRephrasePair struct {
+	Old string `json:"old" yaml:"old"`
+	New string `json:"new" yaml:"new"`
+	// AliasIndex selects which alias to rephrase, for steps that have
+	// aliases. nil (the key absent from the manifest) means "not chosen":
+	// if the step turns out to have aliases, the pair fails with
+	// AliasSelectionRequiredError rather than guessing alias 0.
+	AliasIndex *int `json:"aliasIndex,omitempty" yaml:"aliasIndex,omitempty"`
+}
+
+// performBatchRefactoring reads a YAML or JSON manifest of step rephrase
+// pairs from manifestPath and applies them transactionally: every pair is
+// parsed and validated via getRefactorAgent up front, then
+// rephraseInSpecsAndConcepts is run for each pair in order against the same
+// in-memory specs/conceptDictionary, so later pairs see the rephrasings
+// made by earlier ones. The runner is asked for every pair's step name up
+// front; files are only written, and the runner only asked to refactor,
+// once at the end. On failure every file snapshotted before the
+// write phase is restored.
+func performBatchRefactoring(manifestPath string) []*refactoringResult {
+	pairs, err := readRefactorManifest(manifestPath)
+	if err != nil {
+		return []*refactoringResult{rephraseFailure(err.Error())}
+	}
+
+	agents := make([]*rephraseRefactorer, 0, len(pairs))
+	for _, pair := range pairs {
+		agent, err := getRefactorAgent(pair.Old, pair.New, resolveAliasIndexOption(pair.AliasIndex))
+		if err != nil {
+			return []*refactoringResult{rephraseFailure(fmt.Sprintf("%s -> %s: %s", pair.Old, pair.New, err.Error()))}
+		}
+		agents = append(agents, agent)
+	}
+	if len(agents) == 0 {
+		return []*refactoringResult{rephraseFailure(fmt.Sprintf("Manifest %s contains no step pairs", manifestPath))}
+	}
+
+	projectRoot, err := common.GetProjectRoot()
+	if err != nil {
+		return []*refactoringResult{rephraseFailure(err.Error())}
+	}
+
+	results := make([]*refactoringResult, 0, len(agents))
+	specs, specParseResults := findSpecs(projectRoot, &conceptDictionary{})
+	conceptDictionary, conceptParseResult := createConceptsDictionary(false)
+
+	parseFailures := &refactoringResult{success: true, errors: make([]string, 0), warnings: make([]string, 0)}
+	addErrorsAndWarningsToRefactoringResult(parseFailures, specParseResults...)
+	addErrorsAndWarningsToRefactoringResult(parseFailures, conceptParseResult)
+	if !parseFailures.success {
+		return []*refactoringResult{parseFailures}
+	}
+
+	// The runner has to be consulted for every agent's step name (and,
+	// crucially, its chosen aliasText) *before* rephraseInSpecsAndConcepts
+	// runs - otherwise an aliased step would be renamed against an empty
+	// aliasText, which rewrites every alias's usages instead of just the one
+	// the caller picked. This mirrors the reordering performRefactoringOn in
+	// refactor.go does for the single-step flow.
+	runner, connErr := agents[0].startRunner()
+	if connErr != nil {
+		return []*refactoringResult{rephraseFailure(connErr.Error())}
+	}
+	defer runner.kill()
+
+	stepNames := make([]string, len(agents))
+	for i, agent := range agents {
+		stepName, err := agent.getStepNameFromRunner(runner)
+		if err != nil {
+			return []*refactoringResult{rephraseFailure(fmt.Sprintf("%s -> %s: %s", agent.oldStep.lineText, agent.newStep.lineText, err.Error()))}
+		}
+		stepNames[i] = stepName
+	}
+
+	specsRefactored := make(map[*specification]bool)
+	conceptFilesRefactored := make(map[string]bool)
+	for _, agent := range agents {
+		refactoredSpecs, refactoredConcepts := agent.rephraseInSpecsAndConcepts(&specs, conceptDictionary)
+		for spec, changed := range refactoredSpecs {
+			specsRefactored[spec] = specsRefactored[spec] || changed
+		}
+		for fileName, changed := range refactoredConcepts {
+			conceptFilesRefactored[fileName] = conceptFilesRefactored[fileName] || changed
+		}
+		results = append(results, &refactoringResult{success: true, warnings: parseFailures.warnings})
+	}
+
+	snapshots, err := snapshotFilesToBeWritten(specs, conceptDictionary, specsRefactored, conceptFilesRefactored)
+	if err != nil {
+		return []*refactoringResult{rephraseFailure(err.Error())}
+	}
+
+	specFiles, conceptFiles := writeToConceptAndSpecFiles(specs, conceptDictionary, specsRefactored, conceptFilesRefactored)
+
+	runnerFilesChanged, runnerErr := requestRunnerForBatchRefactoring(runner, agents, stepNames)
+	if runnerErr != nil {
+		errs := []string{fmt.Sprintf("Runner refactoring failed: %s", runnerErr)}
+		if restoreErr := restoreSnapshots(snapshots); restoreErr != nil {
+			errs = append(errs, fmt.Sprintf("Failed to restore files after failed refactor: %s", restoreErr))
+		}
+		return []*refactoringResult{rephraseFailure(errs...)}
+	}
+
+	for _, result := range results {
+		result.specsChanged = specFiles
+		result.conceptsChanged = conceptFiles
+		result.runnerFilesChanged = runnerFilesChanged
+	}
+	return results
+}
+
+// readRefactorManifest parses a batch refactoring manifest. JSON is tried
+// first since every JSON document is also valid YAML input for the YAML
+// parser, which would otherwise mask JSON syntax errors with a confusing
+// YAML-flavoured message.
+func readRefactorManifest(manifestPath string) ([]stepRephrasePair, error) {
+	contents, err := ioutil.ReadFile(manifestPath)
+	if err != nil {
+		return nil, err
+	}
+	var pairs []stepRephrasePair
+	jsonErr := json.Unmarshal(contents, &pairs)
+	if jsonErr == nil {
+		return pairs, nil
+	}
+	if yamlErr := yaml.Unmarshal(contents, &pairs); yamlErr != nil {
+		return nil, fmt.Errorf("failed to parse refactor manifest %s as JSON (%s) or YAML (%s)", manifestPath, jsonErr, yamlErr)
+	}
+	return pairs, nil
+}
+
+// snapshotFilesToBeWritten reads the current contents of every spec and
+// concept file that's about to be rewritten, so they can be restored if the
+// runner round-trip fails.
+func snapshotFilesToBeWritten(specs []*specification, conceptDictionary *conceptDictionary, specsRefactored map[*specification]bool, conceptFilesRefactored map[string]bool) (map[string][]byte, error) {
+	fileNames := make([]string, 0)
+	for _, spec := range specs {
+		if specsRefactored[spec] {
+			fileNames = append(fileNames, spec.fileName)
+		}
+	}
+	for fileName, changed := range conceptFilesRefactored {
+		if changed {
+			fileNames = append(fileNames, fileName)
+		}
+	}
+	return snapshotFiles(fileNames)
+}
+
+// requestRunnerForBatchRefactoring sends every agent's refactor request to
+// runner as a single BatchRefactorRequest, so the runner rewrites its source
+// files once instead of once per pair. stepNames holds, per agent, the name
+// already resolved by performBatchRefactoring's call to
+// getStepNameFromRunner - it's not looked up again here.
+func requestRunnerForBatchRefactoring(runner *testRunner, agents []*rephraseRefactorer, stepNames []string) ([]string, error) {
+	if len(agents) == 0 {
+		return nil, nil
+	}
+
+	requests := make([]*gauge_messages.RefactorRequest, 0, len(agents))
+	for i, agent := range agents {
+		message, err := agent.createRefactorRequest(runner, stepNames[i], false)
+		if err != nil {
+			return nil, err
+		}
+		requests = append(requests, message.GetRefactorRequest())
+	}
+
+	batchRequest := &gauge_messages.Message{
+		MessageType:          gauge_messages.Message_BatchRefactorRequest.Enum(),
+		BatchRefactorRequest: &gauge_messages.BatchRefactorRequest{RefactorRequests: requests},
+	}
+	response, err := getResponseForMessageWithTimeout(batchRequest, runner.connection, config.RefactorTimeout())
+	if err != nil {
+		return nil, err
+	}
+	refactorResponse := response.GetRefactorResponse()
+	if !refactorResponse.GetSuccess() {
+		return nil, errors.New(refactorResponse.GetError())
+	}
+	return refactorResponse.GetFilesChanged(), nil
+}
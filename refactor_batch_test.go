@@ -0,0 +1,115 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeManifest(t *testing.T, dir, name, contents string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, []byte(contents), 0644); err != nil {
+		t.Fatalf("failed to write manifest: %s", err)
+	}
+	return path
+}
+
+func TestReadRefactorManifestPreservesJSONOrder(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gauge-refactor-manifest")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := writeManifest(t, dir, "manifest.json", `[
+		{"old": "first step", "new": "renamed first step"},
+		{"old": "second step", "new": "renamed second step"},
+		{"old": "third step", "new": "renamed third step"}
+	]`)
+
+	pairs, err := readRefactorManifest(path)
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err)
+	}
+	if len(pairs) != 3 {
+		t.Fatalf("expected 3 pairs, got %d", len(pairs))
+	}
+	want := []string{"first step", "second step", "third step"}
+	for i, w := range want {
+		if pairs[i].Old != w {
+			t.Errorf("expected pair %d to be %q, got %q - manifest order must be preserved so later pairs see earlier rephrasings", i, w, pairs[i].Old)
+		}
+	}
+}
+
+func TestReadRefactorManifestPreservesYAMLOrder(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gauge-refactor-manifest")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := writeManifest(t, dir, "manifest.yaml", `
+- old: first step
+  new: renamed first step
+- old: second step
+  new: renamed second step
+`)
+
+	pairs, err := readRefactorManifest(path)
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err)
+	}
+	if len(pairs) != 2 || pairs[0].Old != "first step" || pairs[1].Old != "second step" {
+		t.Fatalf("expected manifest order to be preserved, got %#v", pairs)
+	}
+}
+
+func TestReadRefactorManifestWithAliasIndex(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gauge-refactor-manifest")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := writeManifest(t, dir, "manifest.json", `[{"old": "a step", "new": "a renamed step", "aliasIndex": 1}]`)
+
+	pairs, err := readRefactorManifest(path)
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err)
+	}
+	if len(pairs) != 1 || pairs[0].AliasIndex == nil || *pairs[0].AliasIndex != 1 {
+		t.Fatalf("expected aliasIndex 1 to round-trip, got %#v", pairs)
+	}
+}
+
+func TestReadRefactorManifestRejectsInvalidContent(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gauge-refactor-manifest")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := writeManifest(t, dir, "manifest.json", `not json or yaml: [`)
+
+	if _, err := readRefactorManifest(path); err == nil {
+		t.Fatalf("expected an error for a manifest that is neither valid JSON nor YAML")
+	}
+}
+
+func TestPerformBatchRefactoringRejectsEmptyManifest(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gauge-refactor-manifest")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := writeManifest(t, dir, "manifest.json", `[]`)
+
+	results := performBatchRefactoring(path)
+	if len(results) != 1 || results[0].success {
+		t.Fatalf("expected a single failing result for an empty manifest, got %#v", results)
+	}
+}
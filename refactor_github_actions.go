@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// isGitHubActions reports whether gauge is running as a step inside a
+// GitHub Actions workflow.
+func isGitHubActions() bool {
+	return os.Getenv("GITHUB_ACTIONS") == "true" || os.Getenv("GITHUB_STEP_SUMMARY") != ""
+}
+
+// printRefactoringResultAsGitHubActionsCommands prints result as GitHub
+// Actions workflow commands: errors as ::error::, warnings as ::warning::,
+// and the changed files as a Markdown table to $GITHUB_STEP_SUMMARY.
+func printRefactoringResultAsGitHubActionsCommands(result *refactoringResult) {
+	fmt.Println("::group::specs")
+	for _, fileName := range result.specsChanged {
+		fmt.Printf("%s\n", fileName)
+	}
+	fmt.Println("::endgroup::")
+
+	fmt.Println("::group::concepts")
+	for _, fileName := range result.conceptsChanged {
+		fmt.Printf("%s\n", fileName)
+	}
+	fmt.Println("::endgroup::")
+
+	fmt.Println("::group::runner files")
+	for _, fileName := range result.runnerFilesChanged {
+		fmt.Printf("%s\n", fileName)
+	}
+	fmt.Println("::endgroup::")
+
+	for _, e := range result.errors {
+		fmt.Printf("::error file=%s::%s\n", fileForGitHubAnnotation(e, result), escapeGitHubActionsMessage(e))
+	}
+	for _, w := range result.warnings {
+		fmt.Printf("::warning file=%s::%s\n", fileForGitHubAnnotation(w, result), escapeGitHubActionsMessage(w))
+	}
+
+	writeGitHubStepSummary(result)
+}
+
+// fileForGitHubAnnotation does a best-effort match of message against the
+// files result touched, falling back to "" for a workflow-level annotation.
+func fileForGitHubAnnotation(message string, result *refactoringResult) string {
+	for _, fileName := range result.allFilesChanges() {
+		if strings.Contains(message, fileName) {
+			return fileName
+		}
+	}
+	return ""
+}
+
+func escapeGitHubActionsMessage(message string) string {
+	replacer := strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A")
+	return replacer.Replace(message)
+}
+
+// writeGitHubStepSummary appends a Markdown table of every changed file to
+// the path named by $GITHUB_STEP_SUMMARY, if set.
+func writeGitHubStepSummary(result *refactoringResult) {
+	summaryFile := os.Getenv("GITHUB_STEP_SUMMARY")
+	if summaryFile == "" {
+		return
+	}
+	file, err := os.OpenFile(summaryFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		apiLog.Error("Failed to write GitHub Actions step summary: %s", err.Error())
+		return
+	}
+	defer file.Close()
+
+	fmt.Fprintln(file, "## Gauge refactor")
+	fmt.Fprintln(file, "| File | Type |")
+	fmt.Fprintln(file, "| --- | --- |")
+	for _, fileName := range result.specsChanged {
+		fmt.Fprintf(file, "| %s | spec |\n", fileName)
+	}
+	for _, fileName := range result.conceptsChanged {
+		fmt.Fprintf(file, "| %s | concept |\n", fileName)
+	}
+	for _, fileName := range result.runnerFilesChanged {
+		fmt.Fprintf(file, "| %s | runner |\n", fileName)
+	}
+}
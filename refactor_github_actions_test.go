@@ -0,0 +1,76 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEscapeGitHubActionsMessage(t *testing.T) {
+	escaped := escapeGitHubActionsMessage("100% done\r\nnext line")
+	want := "100%25 done%0D%0Anext line"
+	if escaped != want {
+		t.Errorf("expected %q, got %q", want, escaped)
+	}
+}
+
+func TestFileForGitHubAnnotationMatchesChangedFile(t *testing.T) {
+	result := &refactoringResult{specsChanged: []string{"specs/login.spec"}}
+	file := fileForGitHubAnnotation("failed to parse specs/login.spec: unexpected token", result)
+	if file != "specs/login.spec" {
+		t.Errorf("expected specs/login.spec, got %q", file)
+	}
+}
+
+func TestFileForGitHubAnnotationFallsBackToEmptyString(t *testing.T) {
+	result := &refactoringResult{specsChanged: []string{"specs/login.spec"}}
+	file := fileForGitHubAnnotation("no file mentioned here", result)
+	if file != "" {
+		t.Errorf("expected empty string when no changed file matches, got %q", file)
+	}
+}
+
+func TestWriteGitHubStepSummaryListsEveryChangedFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gauge-refactor-summary")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	summaryFile := filepath.Join(dir, "summary.md")
+	os.Setenv("GITHUB_STEP_SUMMARY", summaryFile)
+	defer os.Unsetenv("GITHUB_STEP_SUMMARY")
+
+	result := &refactoringResult{
+		specsChanged:       []string{"specs/login.spec"},
+		conceptsChanged:    []string{"concepts/login.cpt"},
+		runnerFilesChanged: []string{"step_impl.go"},
+	}
+	writeGitHubStepSummary(result)
+
+	contents, err := ioutil.ReadFile(summaryFile)
+	if err != nil {
+		t.Fatalf("failed to read summary file: %s", err)
+	}
+	for _, want := range []string{"specs/login.spec", "concepts/login.cpt", "step_impl.go"} {
+		if !strings.Contains(string(contents), want) {
+			t.Errorf("expected step summary to mention %q, got:\n%s", want, contents)
+		}
+	}
+}
+
+func TestIsGitHubActions(t *testing.T) {
+	os.Unsetenv("GITHUB_ACTIONS")
+	os.Unsetenv("GITHUB_STEP_SUMMARY")
+	if isGitHubActions() {
+		t.Errorf("expected false when neither env var is set")
+	}
+
+	os.Setenv("GITHUB_ACTIONS", "true")
+	defer os.Unsetenv("GITHUB_ACTIONS")
+	if !isGitHubActions() {
+		t.Errorf("expected true when GITHUB_ACTIONS=true")
+	}
+}
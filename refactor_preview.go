@@ -0,0 +1,260 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// refactoringPreview holds the effect a dry-run refactoring would have had,
+// grouped the same way as refactoringResult: specs, concepts and runner
+// files, each as a fileDiff.
+type refactoringPreview struct {
+	specs       []*fileDiff
+	concepts    []*fileDiff
+	runnerFiles []*fileDiff
+}
+
+// fileDiff is the original and proposed contents of a single file, plus a
+// unified diff between the two.
+type fileDiff struct {
+	fileName string
+	original []byte
+	proposed []byte
+	diff     string
+}
+
+// newFileDiff reads fileName's current contents from disk and diffs them
+// against proposed. If fileName doesn't exist yet original is treated as
+// empty.
+func newFileDiff(fileName, proposed string) (*fileDiff, error) {
+	original, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		if os.IsNotExist(err) {
+			original = []byte{}
+		} else {
+			return nil, err
+		}
+	}
+	return newFileDiffFromBytes(fileName, original, []byte(proposed)), nil
+}
+
+func newFileDiffFromBytes(fileName string, original, proposed []byte) *fileDiff {
+	return &fileDiff{
+		fileName: fileName,
+		original: original,
+		proposed: proposed,
+		diff:     unifiedDiff(fileName, original, proposed),
+	}
+}
+
+// unifiedDiff renders a unified diff between a and b, diff -u style, as a
+// single hunk spanning the whole file. It returns "" when a and b have the
+// same lines.
+func unifiedDiff(fileName string, a, b []byte) string {
+	aLines := splitLines(string(a))
+	bLines := splitLines(string(b))
+	ops := diffLines(aLines, bLines)
+	changed := false
+	for _, op := range ops {
+		if op.kind != diffEqual {
+			changed = true
+			break
+		}
+	}
+	if !changed {
+		return ""
+	}
+
+	aStart, bStart := 1, 1
+	if len(aLines) == 0 {
+		aStart = 0
+	}
+	if len(bLines) == 0 {
+		bStart = 0
+	}
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "--- %s\n", fileName)
+	fmt.Fprintf(&buf, "+++ %s\n", fileName)
+	fmt.Fprintf(&buf, "@@ -%d,%d +%d,%d @@\n", aStart, len(aLines), bStart, len(bLines))
+	for _, op := range ops {
+		switch op.kind {
+		case diffEqual:
+			fmt.Fprintf(&buf, " %s\n", op.line)
+		case diffRemove:
+			fmt.Fprintf(&buf, "-%s\n", op.line)
+		case diffAdd:
+			fmt.Fprintf(&buf, "+%s\n", op.line)
+		}
+	}
+	return buf.String()
+}
+
+type diffOpKind int
+
+const (
+	diffEqual diffOpKind = iota
+	diffRemove
+	diffAdd
+)
+
+type diffOp struct {
+	kind diffOpKind
+	line string
+}
+
+// diffLines computes a line-level diff using the standard LCS dynamic program.
+func diffLines(a, b []string) []diffOp {
+	n, m := len(a), len(b)
+	lcs := make([][]int, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	ops := make([]diffOp, 0, n+m)
+	i, j := 0, 0
+	for i < n && j < m {
+		if a[i] == b[j] {
+			ops = append(ops, diffOp{diffEqual, a[i]})
+			i++
+			j++
+		} else if lcs[i+1][j] >= lcs[i][j+1] {
+			ops = append(ops, diffOp{diffRemove, a[i]})
+			i++
+		} else {
+			ops = append(ops, diffOp{diffAdd, b[j]})
+			j++
+		}
+	}
+	for ; i < n; i++ {
+		ops = append(ops, diffOp{diffRemove, a[i]})
+	}
+	for ; j < m; j++ {
+		ops = append(ops, diffOp{diffAdd, b[j]})
+	}
+	return ops
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	lines := []string{}
+	start := 0
+	for i, r := range s {
+		if r == '\n' {
+			lines = append(lines, s[start:i])
+			start = i + 1
+		}
+	}
+	if start < len(s) {
+		lines = append(lines, s[start:])
+	}
+	return lines
+}
+
+// print writes every diff in the preview to stdout, grouped by category.
+func (preview *refactoringPreview) print() {
+	printFileDiffs("specs", preview.specs)
+	printFileDiffs("concepts", preview.concepts)
+	printFileDiffs("runner files", preview.runnerFiles)
+}
+
+func printFileDiffs(label string, diffs []*fileDiff) {
+	for _, d := range diffs {
+		if d.diff == "" {
+			continue
+		}
+		fmt.Printf("# %s: %s\n%s\n", label, d.fileName, d.diff)
+	}
+}
+
+// snapshotFiles reads the current contents of each named file into a map,
+// to be restored later with restoreSnapshots.
+func snapshotFiles(fileNames []string) (map[string][]byte, error) {
+	snapshots := make(map[string][]byte, len(fileNames))
+	for _, fileName := range fileNames {
+		content, err := ioutil.ReadFile(fileName)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		snapshots[fileName] = content
+	}
+	return snapshots, nil
+}
+
+// snapshotProjectTree reads every regular file under root into a map keyed
+// by path, skipping .git.
+func snapshotProjectTree(root string) (map[string][]byte, error) {
+	snapshots := make(map[string][]byte)
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if info.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		content, readErr := ioutil.ReadFile(path)
+		if readErr != nil {
+			return readErr
+		}
+		snapshots[path] = content
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return snapshots, nil
+}
+
+// restoreSnapshots writes every snapshotted file back to its original path.
+func restoreSnapshots(snapshots map[string][]byte) error {
+	for fileName, content := range snapshots {
+		if err := ioutil.WriteFile(fileName, content, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// restoreProjectSnapshot restores a snapshotProjectTree snapshot over root
+// and removes any file under root the snapshot has no entry for.
+func restoreProjectSnapshot(root string, snapshots map[string][]byte) error {
+	if err := restoreSnapshots(snapshots); err != nil {
+		return err
+	}
+	return filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if info.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if _, ok := snapshots[path]; !ok {
+			return os.Remove(path)
+		}
+		return nil
+	})
+}
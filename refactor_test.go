@@ -0,0 +1,64 @@
+package main
+
+import "testing"
+
+func TestResolveStepNameForAliasWithoutAlias(t *testing.T) {
+	stepName, err := resolveStepNameForAlias([]string{"a step"}, false, -1)
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err)
+	}
+	if stepName != "a step" {
+		t.Errorf("expected stepName %q, got %q", "a step", stepName)
+	}
+}
+
+func TestResolveStepNameForAliasPicksMiddleOfThree(t *testing.T) {
+	aliases := []string{"first alias", "second alias", "third alias"}
+	stepName, err := resolveStepNameForAlias(aliases, true, 1)
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err)
+	}
+	if stepName != "second alias" {
+		t.Errorf("expected only the middle alias to be chosen, got %q", stepName)
+	}
+}
+
+func TestAliasScopedMatchValuePicksMiddleOfThree(t *testing.T) {
+	aliases := []string{"first alias", "second alias", "third alias"}
+	chosen, err := resolveStepNameForAlias(aliases, true, 1)
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err)
+	}
+
+	matchValue := aliasScopedMatchValue("canonical step value", chosen)
+
+	if matchValue != "second alias" {
+		t.Errorf("expected rename to match only the middle alias's usages, got %q", matchValue)
+	}
+	for _, other := range []string{aliases[0], aliases[2]} {
+		if matchValue == other {
+			t.Errorf("expected the other aliases' usages to be left alone, but match value also equals %q", other)
+		}
+	}
+}
+
+func TestAliasScopedMatchValueFallsBackToStepValueWithoutAlias(t *testing.T) {
+	matchValue := aliasScopedMatchValue("canonical step value", "")
+	if matchValue != "canonical step value" {
+		t.Errorf("expected the step's own value when no alias was resolved, got %q", matchValue)
+	}
+}
+
+func TestResolveStepNameForAliasRequiresSelection(t *testing.T) {
+	aliases := []string{"first alias", "second alias", "third alias"}
+
+	_, err := resolveStepNameForAlias(aliases, true, -1)
+	if _, ok := err.(*AliasSelectionRequiredError); !ok {
+		t.Fatalf("expected *AliasSelectionRequiredError when no alias is chosen, got %#v", err)
+	}
+
+	_, err = resolveStepNameForAlias(aliases, true, len(aliases))
+	if _, ok := err.(*AliasSelectionRequiredError); !ok {
+		t.Fatalf("expected *AliasSelectionRequiredError for an out-of-range alias index, got %#v", err)
+	}
+}
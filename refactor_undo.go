@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const refactorSnapshotRoot = ".gauge"
+
+// persistRefactorSnapshot writes every snapshotted file's original bytes
+// into a new .gauge/refactor-<id>/ directory, alongside a manifest.json
+// mapping each original path to its snapshot file, and returns the id.
+func persistRefactorSnapshot(snapshots map[string][]byte) (string, error) {
+	id := fmt.Sprintf("%d", time.Now().UnixNano())
+	dir := filepath.Join(refactorSnapshotRoot, "refactor-"+id)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+
+	manifest := make(map[string]string, len(snapshots))
+	i := 0
+	for originalPath, content := range snapshots {
+		snapshotPath := filepath.Join(dir, fmt.Sprintf("%d", i))
+		if err := ioutil.WriteFile(snapshotPath, content, 0644); err != nil {
+			return "", err
+		}
+		manifest[originalPath] = snapshotPath
+		i++
+	}
+
+	manifestBytes, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "manifest.json"), manifestBytes, 0644); err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// performRefactoringUndo replays the snapshot directory .gauge/refactor-<id>
+// back over the tree, restoring every file it recorded to its pre-refactor
+// contents. This is what `gauge refactor --undo <id>` runs.
+func performRefactoringUndo(id string) error {
+	dir := filepath.Join(refactorSnapshotRoot, "refactor-"+id)
+	manifestBytes, err := ioutil.ReadFile(filepath.Join(dir, "manifest.json"))
+	if err != nil {
+		return fmt.Errorf("no refactor snapshot found for id %s: %s", id, err)
+	}
+
+	var manifest map[string]string
+	if err := json.Unmarshal(manifestBytes, &manifest); err != nil {
+		return fmt.Errorf("corrupt refactor snapshot manifest for id %s: %s", id, err)
+	}
+
+	for originalPath, snapshotPath := range manifest {
+		content, err := ioutil.ReadFile(snapshotPath)
+		if err != nil {
+			return fmt.Errorf("failed to read snapshot of %s: %s", originalPath, err)
+		}
+		if err := ioutil.WriteFile(originalPath, content, 0644); err != nil {
+			return fmt.Errorf("failed to restore %s: %s", originalPath, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// withTempProjectRoot chdirs into a fresh temp directory for the duration of
+// the test, since persistRefactorSnapshot/performRefactoringUndo work off
+// the relative .gauge/refactor-<id>/ path.
+func withTempProjectRoot(t *testing.T) string {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "gauge-refactor-undo")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %s", err)
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("failed to get working directory: %s", err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatalf("failed to chdir into temp dir: %s", err)
+	}
+	t.Cleanup(func() {
+		os.Chdir(cwd)
+		os.RemoveAll(dir)
+	})
+	return dir
+}
+
+func TestPersistRefactorSnapshotAndUndoRoundTrip(t *testing.T) {
+	dir := withTempProjectRoot(t)
+
+	specPath := filepath.Join(dir, "specs", "login.spec")
+	if err := os.MkdirAll(filepath.Dir(specPath), 0755); err != nil {
+		t.Fatalf("failed to create specs dir: %s", err)
+	}
+	original := []byte("Scenario: login\n* first step\n")
+	if err := ioutil.WriteFile(specPath, original, 0644); err != nil {
+		t.Fatalf("failed to write spec file: %s", err)
+	}
+
+	id, err := persistRefactorSnapshot(map[string][]byte{specPath: original})
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err)
+	}
+
+	// Simulate a refactor that rewrote the file.
+	if err := ioutil.WriteFile(specPath, []byte("Scenario: login\n* renamed step\n"), 0644); err != nil {
+		t.Fatalf("failed to simulate refactor write: %s", err)
+	}
+
+	if err := performRefactoringUndo(id); err != nil {
+		t.Fatalf("expected undo to succeed, got %s", err)
+	}
+
+	restored, err := ioutil.ReadFile(specPath)
+	if err != nil {
+		t.Fatalf("failed to read restored spec file: %s", err)
+	}
+	if string(restored) != string(original) {
+		t.Errorf("expected undo to restore original contents %q, got %q", original, restored)
+	}
+}
+
+func TestPerformRefactoringUndoFailsForUnknownID(t *testing.T) {
+	withTempProjectRoot(t)
+
+	if err := performRefactoringUndo("does-not-exist"); err == nil {
+		t.Fatalf("expected an error for a snapshot id that was never persisted")
+	}
+}
+
+func TestRestoreProjectSnapshotRemovesFilesCreatedSinceSnapshot(t *testing.T) {
+	dir := withTempProjectRoot(t)
+
+	existingPath := filepath.Join(dir, "existing.txt")
+	if err := ioutil.WriteFile(existingPath, []byte("original"), 0644); err != nil {
+		t.Fatalf("failed to write existing file: %s", err)
+	}
+
+	snapshots, err := snapshotProjectTree(dir)
+	if err != nil {
+		t.Fatalf("failed to snapshot project tree: %s", err)
+	}
+
+	// Simulate a runner that both edits the existing file and creates a new one.
+	if err := ioutil.WriteFile(existingPath, []byte("edited"), 0644); err != nil {
+		t.Fatalf("failed to edit existing file: %s", err)
+	}
+	newPath := filepath.Join(dir, "new_from_runner.txt")
+	if err := ioutil.WriteFile(newPath, []byte("created by runner"), 0644); err != nil {
+		t.Fatalf("failed to write new file: %s", err)
+	}
+
+	if err := restoreProjectSnapshot(dir, snapshots); err != nil {
+		t.Fatalf("expected no error, got %s", err)
+	}
+
+	restored, err := ioutil.ReadFile(existingPath)
+	if err != nil {
+		t.Fatalf("failed to read restored file: %s", err)
+	}
+	if string(restored) != "original" {
+		t.Errorf("expected existing file restored to %q, got %q", "original", restored)
+	}
+	if _, err := os.Stat(newPath); !os.IsNotExist(err) {
+		t.Errorf("expected the file created after the snapshot to be removed, got err=%v", err)
+	}
+}
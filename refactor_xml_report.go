@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/xml"
+	"io/ioutil"
+)
+
+// refactorJUnitTestSuites mirrors the execution report's JUnitTestSuites
+// shape: one testsuite named "refactor" with one testcase per changed file.
+type refactorJUnitTestSuites struct {
+	XMLName xml.Name             `xml:"testsuites"`
+	Suites  []refactorJUnitSuite `xml:"testsuite"`
+}
+
+type refactorJUnitSuite struct {
+	Name      string              `xml:"name,attr"`
+	Tests     int                 `xml:"tests,attr"`
+	Failures  int                 `xml:"failures,attr"`
+	TestCases []refactorJUnitCase `xml:"testcase"`
+}
+
+type refactorJUnitCase struct {
+	Name      string                `xml:"name,attr"`
+	ClassName string                `xml:"classname,attr"`
+	Failure   *refactorJUnitFailure `xml:"failure,omitempty"`
+	SystemOut []string              `xml:"system-out,omitempty"`
+}
+
+type refactorJUnitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// toJUnitXML serialises result as a JUnit XML document.
+func (result *refactoringResult) toJUnitXML() ([]byte, error) {
+	suite := refactorJUnitSuite{Name: "refactor"}
+
+	addCase := func(fileName, className string) {
+		suite.TestCases = append(suite.TestCases, refactorJUnitCase{Name: fileName, ClassName: className})
+	}
+	for _, fileName := range result.specsChanged {
+		addCase(fileName, "refactor.spec")
+	}
+	for _, fileName := range result.conceptsChanged {
+		addCase(fileName, "refactor.concept")
+	}
+	for _, fileName := range result.runnerFilesChanged {
+		addCase(fileName, "refactor.runner")
+	}
+
+	for _, message := range result.errors {
+		suite.Failures++
+		suite.TestCases = append(suite.TestCases, refactorJUnitCase{
+			Name:      "refactor error",
+			ClassName: "refactor",
+			Failure:   &refactorJUnitFailure{Message: message, Text: message},
+		})
+	}
+
+	if len(result.warnings) > 0 {
+		if len(suite.TestCases) == 0 {
+			addCase("refactor", "refactor")
+		}
+		suite.TestCases[0].SystemOut = append(suite.TestCases[0].SystemOut, result.warnings...)
+	}
+
+	suite.Tests = len(suite.TestCases)
+	suites := refactorJUnitTestSuites{Suites: []refactorJUnitSuite{suite}}
+
+	return xml.MarshalIndent(suites, "", "  ")
+}
+
+// writeJUnitXMLReport serialises result as JUnit XML and writes it to path,
+// for the --report-xml flag.
+func writeJUnitXMLReport(result *refactoringResult, path string) error {
+	contents, err := result.toJUnitXML()
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, contents, 0644)
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/xml"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestToJUnitXMLListsEveryChangedFileAsATestCase(t *testing.T) {
+	result := &refactoringResult{
+		specsChanged:       []string{"specs/login.spec"},
+		conceptsChanged:    []string{"concepts/login.cpt"},
+		runnerFilesChanged: []string{"step_impl.go"},
+	}
+
+	data, err := result.toJUnitXML()
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err)
+	}
+
+	var suites refactorJUnitTestSuites
+	if err := xml.Unmarshal(data, &suites); err != nil {
+		t.Fatalf("failed to parse generated XML: %s", err)
+	}
+	if len(suites.Suites) != 1 {
+		t.Fatalf("expected exactly one testsuite, got %d", len(suites.Suites))
+	}
+	suite := suites.Suites[0]
+	if suite.Tests != 3 {
+		t.Errorf("expected 3 tests, got %d", suite.Tests)
+	}
+	if suite.Failures != 0 {
+		t.Errorf("expected 0 failures, got %d", suite.Failures)
+	}
+	if len(suite.TestCases) != 3 {
+		t.Fatalf("expected 3 testcases, got %d", len(suite.TestCases))
+	}
+	if suite.TestCases[0].ClassName != "refactor.spec" || suite.TestCases[0].Name != "specs/login.spec" {
+		t.Errorf("expected first testcase to be the changed spec, got %#v", suite.TestCases[0])
+	}
+}
+
+func TestToJUnitXMLRecordsErrorsAsFailures(t *testing.T) {
+	result := &refactoringResult{errors: []string{"step implementation not found"}}
+
+	data, err := result.toJUnitXML()
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err)
+	}
+
+	var suites refactorJUnitTestSuites
+	if err := xml.Unmarshal(data, &suites); err != nil {
+		t.Fatalf("failed to parse generated XML: %s", err)
+	}
+	suite := suites.Suites[0]
+	if suite.Failures != 1 {
+		t.Errorf("expected 1 failure, got %d", suite.Failures)
+	}
+	if len(suite.TestCases) != 1 || suite.TestCases[0].Failure == nil {
+		t.Fatalf("expected a testcase with a failure, got %#v", suite.TestCases)
+	}
+	if suite.TestCases[0].Failure.Message != "step implementation not found" {
+		t.Errorf("expected failure message to be the error text, got %q", suite.TestCases[0].Failure.Message)
+	}
+}
+
+func TestToJUnitXMLAttachesWarningsToFirstTestCase(t *testing.T) {
+	result := &refactoringResult{warnings: []string{"unused parameter"}}
+
+	data, err := result.toJUnitXML()
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err)
+	}
+
+	var suites refactorJUnitTestSuites
+	if err := xml.Unmarshal(data, &suites); err != nil {
+		t.Fatalf("failed to parse generated XML: %s", err)
+	}
+	suite := suites.Suites[0]
+	if len(suite.TestCases) != 1 {
+		t.Fatalf("expected a placeholder testcase to hold the warning, got %#v", suite.TestCases)
+	}
+	if len(suite.TestCases[0].SystemOut) != 1 || suite.TestCases[0].SystemOut[0] != "unused parameter" {
+		t.Errorf("expected the warning on the first testcase's system-out, got %#v", suite.TestCases[0].SystemOut)
+	}
+}
+
+func TestWriteJUnitXMLReportWritesToPath(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gauge-refactor-xml")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "report.xml")
+	result := &refactoringResult{specsChanged: []string{"specs/login.spec"}}
+	if err := writeJUnitXMLReport(result, path); err != nil {
+		t.Fatalf("expected no error, got %s", err)
+	}
+
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("expected report file to exist: %s", err)
+	}
+	if len(contents) == 0 {
+		t.Errorf("expected non-empty report file")
+	}
+}